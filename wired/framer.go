@@ -0,0 +1,119 @@
+package wired
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+// p7Field is a single <field> within a P7 message.
+type p7Field struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:",chardata"`
+}
+
+// p7Message is the decoded shape of a P7 message read from the wire.
+//
+// It deliberately has no XMLName field: the server's messages declare the
+// "p7" namespace prefix via an xmlns:p7 attribute, and encoding/xml only
+// checks an element's resolved name against XMLName when one is present, so
+// leaving it out lets DecodeElement bind <p7:message>/<p7:field> elements
+// without having to reproduce Go's namespace resolution by hand.
+type p7Message struct {
+	Name   string    `xml:"name,attr"`
+	Fields []p7Field `xml:"field"`
+}
+
+// outboundField and outboundMessage mirror p7Field/p7Message but carry an
+// explicit XMLName so xml.Marshal knows to write "p7:message"/"p7:field"
+// rather than a name derived from the Go type. They're a separate type from
+// p7Message/p7Field (rather than reusing them for both directions) because
+// an XMLName set for Marshal's sake would make DecodeElement reject
+// perfectly good inbound messages whose names resolve through the xmlns:p7
+// namespace declaration instead of matching literally.
+type outboundField struct {
+	XMLName xml.Name `xml:"p7:field"`
+	Name    string   `xml:"name,attr"`
+	Value   string   `xml:",chardata"`
+}
+
+type outboundMessage struct {
+	XMLName xml.Name        `xml:"p7:message"`
+	Name    string          `xml:"name,attr"`
+	XMLNS   string          `xml:"xmlns:p7,attr"`
+	Fields  []outboundField `xml:"p7:field"`
+}
+
+const p7Namespace = "http://www.zankasoftware.com/P7/Message"
+
+// Framer reads and writes P7 messages on a connection's socket.
+//
+// Wired traditionally delimits messages with a trailing \r, but that byte
+// can appear unescaped inside a field's value (the compatibility spec is a
+// notorious example), so Framer never scans for it. Instead it drives an
+// encoding/xml.Decoder directly off the socket and lets the XML grammar
+// itself mark where one message ends and the next begins. That also means a
+// single large message (the compatibility spec can run past 20 MB) streams
+// through the decoder instead of being buffered whole before being handed
+// to encoding/xml.
+type Framer struct {
+	decoder *xml.Decoder
+	writer  io.Writer
+}
+
+// NewFramer wraps rw for reading and writing P7 messages.
+func NewFramer(rw io.ReadWriter) *Framer {
+	return &Framer{
+		decoder: xml.NewDecoder(rw),
+		writer:  rw,
+	}
+}
+
+// ReadMessage blocks until the next complete P7 message has been decoded
+// from the underlying reader.
+func (f *Framer) ReadMessage() (*p7Message, error) {
+	for {
+		token, err := f.decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		message := new(p7Message)
+		if err := f.decoder.DecodeElement(message, &start); err != nil {
+			return nil, err
+		}
+
+		return message, nil
+	}
+}
+
+// WriteMessage marshals transaction/parameters as a P7 message and writes
+// it to the underlying writer, escaping field values instead of
+// concatenating them into the XML as raw strings.
+func (f *Framer) WriteMessage(transaction string, parameters map[string]string) error {
+	message := outboundMessage{
+		Name:  transaction,
+		XMLNS: p7Namespace,
+	}
+
+	for key, value := range parameters {
+		message.Fields = append(message.Fields, outboundField{Name: key, Value: value})
+	}
+
+	payload, err := xml.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	// Wired expects an XML declaration ahead of every message and a \r\n to
+	// mark the end of it.
+	framed := append([]byte(`<?xml version="1.0" encoding="UTF-8"?>`), payload...)
+	framed = append(framed, '\r', '\n')
+
+	_, err = f.writer.Write(framed)
+	return err
+}
@@ -0,0 +1,102 @@
+package wired
+
+import (
+	"fmt"
+	"log"
+	"os"
+)
+
+// logLevel orders the severities a Logger can be asked to emit.
+type logLevel int
+
+const (
+	levelTrace logLevel = iota
+	levelDebug
+	levelInfo
+	levelError
+)
+
+// Logger is the structured logging interface used throughout the wired
+// package. Every line is a short message plus a flat list of key/value
+// pairs, so it can be satisfied by log15, logrus, slog, or anything else
+// with the same shape. With returns a child Logger that carries additional
+// fields (e.g. "server", "user_id") on every subsequent call.
+type Logger interface {
+	Trace(msg string, keyvals ...interface{})
+	Debug(msg string, keyvals ...interface{})
+	Info(msg string, keyvals ...interface{})
+	Error(msg string, keyvals ...interface{})
+	With(keyvals ...interface{}) Logger
+}
+
+// defaultLogger is a minimal Logger built on the standard library's log
+// package. It's used whenever a Connection doesn't have a Logger configured.
+type defaultLogger struct {
+	std    *log.Logger
+	level  logLevel
+	fields []interface{}
+}
+
+// NewDefaultLogger returns a Logger that writes key/value formatted lines to
+// stderr. Trace and Debug lines are suppressed, and child Loggers returned by
+// With keep that same level; there is no way to change it on this Logger.
+func NewDefaultLogger() Logger {
+	return &defaultLogger{
+		std:   log.New(os.Stderr, "", log.LstdFlags),
+		level: levelInfo,
+	}
+}
+
+func (l *defaultLogger) log(level logLevel, prefix, msg string, keyvals []interface{}) {
+	if level < l.level {
+		return
+	}
+
+	line := prefix + " " + msg
+	for _, kv := range formatKeyvals(append(append([]interface{}{}, l.fields...), keyvals...)) {
+		line += " " + kv
+	}
+
+	l.std.Println(line)
+}
+
+func (l *defaultLogger) Trace(msg string, keyvals ...interface{}) {
+	l.log(levelTrace, "trace", msg, keyvals)
+}
+
+func (l *defaultLogger) Debug(msg string, keyvals ...interface{}) {
+	l.log(levelDebug, "debug", msg, keyvals)
+}
+
+func (l *defaultLogger) Info(msg string, keyvals ...interface{}) {
+	l.log(levelInfo, "info", msg, keyvals)
+}
+
+func (l *defaultLogger) Error(msg string, keyvals ...interface{}) {
+	l.log(levelError, "error", msg, keyvals)
+}
+
+func (l *defaultLogger) With(keyvals ...interface{}) Logger {
+	return &defaultLogger{
+		std:    l.std,
+		level:  l.level,
+		fields: append(append([]interface{}{}, l.fields...), keyvals...),
+	}
+}
+
+// formatKeyvals pairs up an alternating key, value, key, value... slice into
+// "key=value" strings. A trailing key with no matching value is rendered on
+// its own.
+func formatKeyvals(keyvals []interface{}) []string {
+	pairs := make([]string, 0, len(keyvals)/2+1)
+
+	for i := 0; i < len(keyvals); i += 2 {
+		if i+1 < len(keyvals) {
+			pairs = append(pairs, fmt.Sprintf("%v=%v", keyvals[i], keyvals[i+1]))
+		} else {
+			pairs = append(pairs, fmt.Sprintf("%v", keyvals[i]))
+		}
+	}
+
+	return pairs
+}
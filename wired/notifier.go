@@ -0,0 +1,63 @@
+package wired
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// EventType identifies what happened on a Wired server that a Notifier
+// might want to tell someone about.
+type EventType int
+
+const (
+	// EventUserJoin fires when a user joins a channel.
+	EventUserJoin EventType = iota
+	// EventUserPart fires when a user leaves a channel.
+	EventUserPart
+	// EventMention fires when a chat message mentions the logged-in user.
+	EventMention
+	// EventPrivateMessage fires when a private message is received.
+	EventPrivateMessage
+)
+
+// Event describes something a Notifier should be told about.
+type Event struct {
+	Type EventType
+
+	// Server is the "host:port" of the Wired server the event came from.
+	Server string
+
+	Nick    string
+	Channel string
+	Message string
+
+	Time time.Time
+}
+
+// Notifier delivers an Event somewhere outside of the Wired connection
+// itself. APNSNotifier, FCMNotifier, WebPushNotifier, and WebhookNotifier
+// all satisfy it; a server can stack several per Connection so one event
+// fans out to every configured backend instead of only a single hard-coded
+// APNs device.
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// notify fans an Event out to every Notifier configured on the connection.
+// Each Notifier is given its own goroutine (through safeGo) so a slow or
+// failing backend can't hold up the others or this connection's processData
+// loop.
+func (conn *Connection) notify(event Event) {
+	event.Server = conn.Host + ":" + strconv.Itoa(conn.Port)
+
+	for _, notifier := range conn.Notifiers {
+		notifier := notifier
+
+		conn.safeGo(func() {
+			if err := notifier.Notify(conn.ctx, event); err != nil {
+				conn.Logger.Error("Notifier failed", "error", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package wired
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mattprice/Go-APNs"
+)
+
+// APNSNotifier pushes EventUserJoin notifications to a fixed list of iOS
+// device tokens through Apple Push Notification service.
+//
+// This replaces the package-level apns.LoadCertificateFile call that used to
+// run unconditionally in init() for every server the process happened to be
+// built for; a server only pays for APNs setup when NewAPNSNotifier is
+// actually called.
+type APNSNotifier struct {
+	DeviceTokens []string
+	Sandbox      bool
+}
+
+// NewAPNSNotifier loads certFile and returns a Notifier that pushes to
+// deviceTokens whenever it sees an EventUserJoin.
+func NewAPNSNotifier(certFile string, sandbox bool, deviceTokens []string) (*APNSNotifier, error) {
+	if err := apns.LoadCertificateFile(sandbox, certFile); err != nil {
+		return nil, fmt.Errorf("loading APNs certificate: %v", err)
+	}
+
+	return &APNSNotifier{
+		DeviceTokens: deviceTokens,
+		Sandbox:      sandbox,
+	}, nil
+}
+
+// Notify implements Notifier. Only EventUserJoin is currently pushed; other
+// event types are silently ignored.
+func (n *APNSNotifier) Notify(ctx context.Context, event Event) error {
+	if event.Type != EventUserJoin {
+		return nil
+	}
+
+	payload := &apns.Notification{
+		Alert:   fmt.Sprintf("%s has logged into %s.", event.Nick, event.Server),
+		Sandbox: n.Sandbox,
+	}
+	payload.SetExpiryDuration(24 * time.Hour)
+
+	for _, token := range n.DeviceTokens {
+		payload.SendTo(token)
+	}
+
+	return nil
+}
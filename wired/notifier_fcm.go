@@ -0,0 +1,107 @@
+package wired
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// TokenSource supplies the bearer token used to authenticate requests to the
+// Firebase Cloud Messaging HTTP v1 API. Callers typically wrap
+// golang.org/x/oauth2's TokenSource with this interface, but any
+// implementation that can mint a short-lived OAuth2 access token works.
+type TokenSource interface {
+	Token() (string, error)
+}
+
+// FCMNotifier pushes notifications through Firebase Cloud Messaging's
+// HTTP v1 API.
+type FCMNotifier struct {
+	// ProjectID is the Firebase project ID, used to build the v1 send URL.
+	ProjectID string
+
+	// Tokens supplies the OAuth2 bearer token for each request.
+	Tokens TokenSource
+
+	// DeviceTokens are the FCM registration tokens to push to.
+	DeviceTokens []string
+
+	// HTTPClient is used to make requests. http.DefaultClient is used if nil.
+	HTTPClient *http.Client
+}
+
+func (n *FCMNotifier) httpClient() *http.Client {
+	if n.HTTPClient != nil {
+		return n.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// Notify implements Notifier, sending one FCM message per configured device
+// token.
+func (n *FCMNotifier) Notify(ctx context.Context, event Event) error {
+	token, err := n.Tokens.Token()
+	if err != nil {
+		return fmt.Errorf("getting FCM OAuth2 token: %v", err)
+	}
+
+	url := fmt.Sprintf("https://fcm.googleapis.com/v1/projects/%s/messages:send", n.ProjectID)
+	body := eventNotificationBody(event)
+
+	for _, deviceToken := range n.DeviceTokens {
+		message := map[string]interface{}{
+			"message": map[string]interface{}{
+				"token": deviceToken,
+				"notification": map[string]string{
+					"title": "Wired",
+					"body":  body,
+				},
+			},
+		}
+
+		payload, err := json.Marshal(message)
+		if err != nil {
+			return fmt.Errorf("encoding FCM message: %v", err)
+		}
+
+		req, err := http.NewRequest("POST", url, bytes.NewReader(payload))
+		if err != nil {
+			return fmt.Errorf("building FCM request: %v", err)
+		}
+		req = req.WithContext(ctx)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := n.httpClient().Do(req)
+		if err != nil {
+			return fmt.Errorf("sending FCM request: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("FCM request failed with status %s", resp.Status)
+		}
+	}
+
+	return nil
+}
+
+// eventNotificationBody renders a human-readable message for the given
+// event. Shared by the FCM and Web Push notifiers so they agree on wording;
+// the webhook notifier sends the event's raw fields instead.
+func eventNotificationBody(event Event) string {
+	switch event.Type {
+	case EventUserJoin:
+		return fmt.Sprintf("%s has logged into %s.", event.Nick, event.Server)
+	case EventUserPart:
+		return fmt.Sprintf("%s has left %s.", event.Nick, event.Server)
+	case EventMention:
+		return fmt.Sprintf("%s mentioned you: %s", event.Nick, event.Message)
+	case EventPrivateMessage:
+		return fmt.Sprintf("%s sent you a message: %s", event.Nick, event.Message)
+	default:
+		return fmt.Sprintf("%s: %s", event.Nick, event.Message)
+	}
+}
@@ -0,0 +1,85 @@
+package wired
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// WebhookNotifier POSTs a JSON representation of each Event to a configured
+// URL, for integrations that don't fit any of the other Notifier
+// implementations (Slack-style incoming webhooks, internal dashboards, etc.).
+type WebhookNotifier struct {
+	URL string
+
+	// Headers are added to every outbound request, e.g. for an
+	// Authorization token the receiving end expects.
+	Headers map[string]string
+
+	HTTPClient *http.Client
+}
+
+func (n *WebhookNotifier) httpClient() *http.Client {
+	if n.HTTPClient != nil {
+		return n.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// webhookPayload is the JSON body POSTed to the webhook URL.
+type webhookPayload struct {
+	Type    string `json:"type"`
+	Server  string `json:"server"`
+	Nick    string `json:"nick,omitempty"`
+	Channel string `json:"channel,omitempty"`
+	Message string `json:"message,omitempty"`
+	Time    string `json:"time"`
+}
+
+var eventTypeNames = map[EventType]string{
+	EventUserJoin:       "user_join",
+	EventUserPart:       "user_part",
+	EventMention:        "mention",
+	EventPrivateMessage: "private_message",
+}
+
+// Notify implements Notifier.
+func (n *WebhookNotifier) Notify(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(webhookPayload{
+		Type:    eventTypeNames[event.Type],
+		Server:  event.Server,
+		Nick:    event.Nick,
+		Channel: event.Channel,
+		Message: event.Message,
+		Time:    event.Time.Format(time.RFC3339),
+	})
+	if err != nil {
+		return fmt.Errorf("encoding webhook payload: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", n.URL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("building webhook request: %v", err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range n.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := n.httpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("sending webhook request: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %s", resp.Status)
+	}
+
+	return nil
+}
+
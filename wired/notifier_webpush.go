@@ -0,0 +1,255 @@
+package wired
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// WebPushSubscription is the subscription a browser hands back from
+// PushManager.subscribe(), as needed to encrypt and address a Web Push
+// message per RFC 8291/8188.
+type WebPushSubscription struct {
+	Endpoint string
+
+	// P256dh and Auth are the base64url-encoded "p256dh" and "auth" keys
+	// from the subscription's keys object.
+	P256dh string
+	Auth   string
+}
+
+// WebPushNotifier sends notifications to browsers via Web Push, encrypting
+// each message with aes128gcm (RFC 8188) and authenticating requests with a
+// VAPID JWT (RFC 8292).
+type WebPushNotifier struct {
+	Subscriptions []WebPushSubscription
+
+	// VAPIDPrivateKey signs the Authorization JWT sent with every request.
+	// Its public key is advertised to the push service in the "k" parameter.
+	VAPIDPrivateKey *ecdsa.PrivateKey
+
+	// VAPIDSubject identifies the sender to the push service, e.g.
+	// "mailto:ops@example.com".
+	VAPIDSubject string
+
+	HTTPClient *http.Client
+}
+
+func (n *WebPushNotifier) httpClient() *http.Client {
+	if n.HTTPClient != nil {
+		return n.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// Notify implements Notifier, sending one encrypted push per subscription.
+func (n *WebPushNotifier) Notify(ctx context.Context, event Event) error {
+	plaintext := []byte(eventNotificationBody(event))
+
+	for _, sub := range n.Subscriptions {
+		if err := n.send(ctx, sub, plaintext); err != nil {
+			return fmt.Errorf("sending web push to %s: %v", sub.Endpoint, err)
+		}
+	}
+
+	return nil
+}
+
+func (n *WebPushNotifier) send(ctx context.Context, sub WebPushSubscription, plaintext []byte) error {
+	body, err := encryptWebPush(sub, plaintext)
+	if err != nil {
+		return fmt.Errorf("encrypting payload: %v", err)
+	}
+
+	authHeader, err := vapidAuthorizationHeader(n.VAPIDPrivateKey, n.VAPIDSubject, sub.Endpoint)
+	if err != nil {
+		return fmt.Errorf("building VAPID header: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("building request: %v", err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", "86400")
+	req.Header.Set("Authorization", authHeader)
+
+	resp, err := n.httpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("sending request: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("push service returned status %s", resp.Status)
+	}
+
+	return nil
+}
+
+// encryptWebPush encrypts plaintext for sub following the aes128gcm content
+// coding defined by RFC 8188, with the key derivation from RFC 8291.
+func encryptWebPush(sub WebPushSubscription, plaintext []byte) ([]byte, error) {
+	clientPublicKey, err := base64.RawURLEncoding.DecodeString(sub.P256dh)
+	if err != nil {
+		return nil, fmt.Errorf("decoding p256dh: %v", err)
+	}
+
+	authSecret, err := base64.RawURLEncoding.DecodeString(sub.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("decoding auth secret: %v", err)
+	}
+
+	curve := elliptic.P256()
+
+	clientX, clientY := elliptic.Unmarshal(curve, clientPublicKey)
+	if clientX == nil {
+		return nil, fmt.Errorf("invalid p256dh public key")
+	}
+
+	serverPrivate, serverX, serverY, err := elliptic.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generating ephemeral key: %v", err)
+	}
+	serverPublicKey := elliptic.Marshal(curve, serverX, serverY)
+
+	sharedX, _ := curve.ScalarMult(clientX, clientY, serverPrivate)
+	ecdhSecret := sharedX.Bytes()
+	ecdhSecret = leftPad(ecdhSecret, (curve.Params().BitSize+7)/8)
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("generating salt: %v", err)
+	}
+
+	// Derive the content-encryption key and nonce per RFC 8291 section 3.4.
+	keyInfo := append([]byte("WebPush: info\x00"), clientPublicKey...)
+	keyInfo = append(keyInfo, serverPublicKey...)
+	ikm := hkdf(authSecret, ecdhSecret, keyInfo, 32)
+
+	prk := hkdfExtract(salt, ikm)
+	cek := hkdfExpand(prk, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce := hkdfExpand(prk, []byte("Content-Encoding: nonce\x00"), 12)
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, fmt.Errorf("creating AES cipher: %v", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("creating GCM: %v", err)
+	}
+
+	// A single aes128gcm record: the plaintext followed by the 0x02
+	// delimiter that marks it as the last (and only) record.
+	record := append(append([]byte{}, plaintext...), 0x02)
+	ciphertext := gcm.Seal(nil, nonce, record, nil)
+
+	// RFC 8188 header: salt || record size || key id length || key id.
+	header := make([]byte, 16+4+1)
+	copy(header, salt)
+	binary.BigEndian.PutUint32(header[16:20], uint32(len(ciphertext)+len(header)+len(serverPublicKey)))
+	header[20] = byte(len(serverPublicKey))
+	header = append(header, serverPublicKey...)
+
+	return append(header, ciphertext...), nil
+}
+
+// leftPad pads buf with leading zero bytes until it's size long, matching
+// the fixed-width big-endian encoding ECDH shared secrets require.
+func leftPad(buf []byte, size int) []byte {
+	if len(buf) >= size {
+		return buf
+	}
+
+	padded := make([]byte, size)
+	copy(padded[size-len(buf):], buf)
+	return padded
+}
+
+// hkdfExtract implements the HKDF-Extract step from RFC 5869.
+func hkdfExtract(salt, ikm []byte) []byte {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write(ikm)
+	return mac.Sum(nil)
+}
+
+// hkdfExpand implements the HKDF-Expand step from RFC 5869 for an output no
+// longer than a single SHA-256 block (32 bytes), which is all this package
+// needs.
+func hkdfExpand(prk, info []byte, length int) []byte {
+	mac := hmac.New(sha256.New, prk)
+	mac.Write(info)
+	mac.Write([]byte{0x01})
+	return mac.Sum(nil)[:length]
+}
+
+// hkdf runs HKDF-Extract followed by HKDF-Expand.
+func hkdf(salt, ikm, info []byte, length int) []byte {
+	return hkdfExpand(hkdfExtract(salt, ikm), info, length)
+}
+
+// vapidAuthorizationHeader builds the "vapid t=<jwt>, k=<key>" Authorization
+// header defined by RFC 8292.
+func vapidAuthorizationHeader(key *ecdsa.PrivateKey, subject, endpoint string) (string, error) {
+	audience, err := endpointOrigin(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`))
+
+	claims, err := json.Marshal(map[string]interface{}{
+		"aud": audience,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": subject,
+	})
+	if err != nil {
+		return "", fmt.Errorf("encoding VAPID claims: %v", err)
+	}
+
+	signingInput := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("signing VAPID JWT: %v", err)
+	}
+
+	size := (key.Curve.Params().BitSize + 7) / 8
+	signature := append(leftPad(r.Bytes(), size), leftPad(s.Bytes(), size)...)
+
+	jwt := signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+
+	publicKey := elliptic.Marshal(key.Curve, key.PublicKey.X, key.PublicKey.Y)
+	k := base64.RawURLEncoding.EncodeToString(publicKey)
+
+	return fmt.Sprintf("vapid t=%s, k=%s", jwt, k), nil
+}
+
+// endpointOrigin returns the scheme://host audience a push service expects
+// in the VAPID JWT, e.g. "https://fcm.googleapis.com" for an FCM endpoint.
+func endpointOrigin(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("parsing endpoint %q: %v", endpoint, err)
+	}
+
+	return u.Scheme + "://" + u.Host, nil
+}
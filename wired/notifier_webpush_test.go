@@ -0,0 +1,197 @@
+package wired
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// TestHKDF checks hkdfExtract/hkdfExpand against RFC 5869 appendix A.1 test
+// case 1, truncated to the 32 bytes hkdfExpand supports.
+func TestHKDF(t *testing.T) {
+	ikm := bytes.Repeat([]byte{0x0b}, 22)
+	salt := mustHex(t, "000102030405060708090a0b0c")
+	info := mustHex(t, "f0f1f2f3f4f5f6f7f8f9")
+
+	wantPRK := mustHex(t, "077709362c2e32df0ddc3f0dc47bba6390b6c73bb50f9c3122ec844ad7c2b3e5")
+	wantOKM := mustHex(t, "3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf")
+
+	prk := hkdfExtract(salt, ikm)
+	if !bytes.Equal(prk, wantPRK) {
+		t.Fatalf("hkdfExtract = %x, want %x", prk, wantPRK)
+	}
+
+	okm := hkdfExpand(prk, info, 32)
+	if !bytes.Equal(okm, wantOKM) {
+		t.Fatalf("hkdfExpand = %x, want %x", okm, wantOKM)
+	}
+}
+
+// TestEncryptWebPushRoundTrip encrypts a message the way Notify would, then
+// decrypts it the way a browser's push service worker would: deriving the
+// same aes128gcm key material independently from the client's private key
+// and the header encryptWebPush wrote, instead of reaching into
+// encryptWebPush's internals.
+func TestEncryptWebPushRoundTrip(t *testing.T) {
+	curve := elliptic.P256()
+
+	clientPrivate, clientX, clientY, err := elliptic.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatalf("generating client key: %v", err)
+	}
+	clientPublicKey := elliptic.Marshal(curve, clientX, clientY)
+
+	authSecret := make([]byte, 16)
+	if _, err := rand.Read(authSecret); err != nil {
+		t.Fatalf("generating auth secret: %v", err)
+	}
+
+	sub := WebPushSubscription{
+		P256dh: base64.RawURLEncoding.EncodeToString(clientPublicKey),
+		Auth:   base64.RawURLEncoding.EncodeToString(authSecret),
+	}
+
+	plaintext := []byte("hello from Wired")
+
+	payload, err := encryptWebPush(sub, plaintext)
+	if err != nil {
+		t.Fatalf("encryptWebPush: %v", err)
+	}
+
+	// Parse the RFC 8188 aes128gcm header: salt || record size || key id
+	// length || key id (the server's ephemeral public key).
+	if len(payload) < 21 {
+		t.Fatalf("payload too short: %d bytes", len(payload))
+	}
+	salt := payload[:16]
+	recordSize := binary.BigEndian.Uint32(payload[16:20])
+	keyIDLen := int(payload[20])
+	serverPublicKey := payload[21 : 21+keyIDLen]
+	ciphertext := payload[21+keyIDLen:]
+
+	if int(recordSize) != len(payload) {
+		t.Fatalf("record size = %d, want %d (total payload length)", recordSize, len(payload))
+	}
+
+	serverX, serverY := elliptic.Unmarshal(curve, serverPublicKey)
+	if serverX == nil {
+		t.Fatalf("invalid server public key in header")
+	}
+
+	sharedX, _ := curve.ScalarMult(serverX, serverY, clientPrivate)
+	ecdhSecret := leftPad(sharedX.Bytes(), (curve.Params().BitSize+7)/8)
+
+	keyInfo := append([]byte("WebPush: info\x00"), clientPublicKey...)
+	keyInfo = append(keyInfo, serverPublicKey...)
+	ikm := hkdf(authSecret, ecdhSecret, keyInfo, 32)
+
+	prk := hkdfExtract(salt, ikm)
+	cek := hkdfExpand(prk, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce := hkdfExpand(prk, []byte("Content-Encoding: nonce\x00"), 12)
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		t.Fatalf("creating AES cipher: %v", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatalf("creating GCM: %v", err)
+	}
+
+	record, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		t.Fatalf("decrypting record: %v", err)
+	}
+
+	if len(record) == 0 || record[len(record)-1] != 0x02 {
+		t.Fatalf("record missing the 0x02 last-record delimiter: %x", record)
+	}
+
+	got := record[:len(record)-1]
+	if !bytes.Equal(got, plaintext) {
+		t.Fatalf("decrypted = %q, want %q", got, plaintext)
+	}
+}
+
+// TestVapidAuthorizationHeader checks that the header's JWT is signed with
+// the given key and carries the expected claims.
+func TestVapidAuthorizationHeader(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating VAPID key: %v", err)
+	}
+
+	header, err := vapidAuthorizationHeader(key, "mailto:ops@example.com", "https://fcm.googleapis.com/fcm/send/abc123")
+	if err != nil {
+		t.Fatalf("vapidAuthorizationHeader: %v", err)
+	}
+
+	if !strings.HasPrefix(header, "vapid t=") {
+		t.Fatalf("header = %q, want a leading \"vapid t=\"", header)
+	}
+
+	tPart := strings.TrimPrefix(strings.SplitN(header, ", k=", 2)[0], "vapid t=")
+	parts := strings.Split(tPart, ".")
+	if len(parts) != 3 {
+		t.Fatalf("jwt has %d parts, want 3", len(parts))
+	}
+
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatalf("decoding claims: %v", err)
+	}
+
+	var claims struct {
+		Audience string `json:"aud"`
+		Subject  string `json:"sub"`
+	}
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		t.Fatalf("unmarshalling claims: %v", err)
+	}
+	if claims.Audience != "https://fcm.googleapis.com" {
+		t.Fatalf("aud = %q, want %q", claims.Audience, "https://fcm.googleapis.com")
+	}
+	if claims.Subject != "mailto:ops@example.com" {
+		t.Fatalf("sub = %q, want %q", claims.Subject, "mailto:ops@example.com")
+	}
+
+	signingInput := parts[0] + "." + parts[1]
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatalf("decoding signature: %v", err)
+	}
+
+	size := (key.Curve.Params().BitSize + 7) / 8
+	if len(signature) != 2*size {
+		t.Fatalf("signature is %d bytes, want %d", len(signature), 2*size)
+	}
+
+	r := new(big.Int).SetBytes(signature[:size])
+	s := new(big.Int).SetBytes(signature[size:])
+
+	digest := sha256.Sum256([]byte(signingInput))
+	if !ecdsa.Verify(&key.PublicKey, digest[:], r, s) {
+		t.Fatalf("signature does not verify against the VAPID public key")
+	}
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("decoding hex %q: %v", s, err)
+	}
+	return b
+}
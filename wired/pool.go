@@ -0,0 +1,204 @@
+package wired
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ServerConfig describes a single Wired server a Pool should maintain a
+// connection to: where it lives, how to authenticate, which channels to
+// join, and which Notifiers should be told about its activity.
+type ServerConfig struct {
+	Host string
+	Port int
+
+	User     string
+	Password string
+
+	Channels  []string
+	Notifiers []Notifier
+
+	// Logger receives this server's Connection's log lines. If nil, the
+	// Pool's own Logger is used instead, the same way a bare Connection
+	// defaults to NewDefaultLogger when none is set.
+	Logger Logger
+}
+
+// id returns the key a Pool uses to identify the connection for this config.
+func (cfg ServerConfig) id() string {
+	return fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
+}
+
+// Pool maintains persistent connections to many Wired servers concurrently.
+// Each server gets its own *Connection, keyed by "host:port", so a single
+// push server process can watch an arbitrary number of Wired servers at once.
+type Pool struct {
+	mu          sync.Mutex
+	connections map[string]*Connection
+
+	// pending holds the ids whose AddServer call is still inside Connect(),
+	// i.e. whose Connection may not have a socket or framer yet. RemoveServer
+	// consults this to avoid racing a Disconnect against an in-flight
+	// Connect (see AddServer).
+	pending map[string]bool
+
+	// Logger receives every log line the Pool itself produces (adding or
+	// removing a server, giving up on one, ...), as opposed to lines from a
+	// given server's own Connection. If nil, logger lazily defaults it to a
+	// Logger built by NewDefaultLogger the same way Connect does for a bare
+	// Connection.
+	Logger Logger
+}
+
+// NewPool creates an empty Pool.
+func NewPool() *Pool {
+	return &Pool{
+		connections: make(map[string]*Connection),
+		pending:     make(map[string]bool),
+	}
+}
+
+// logger returns p.Logger, defaulting and caching it the first time it's
+// needed.
+func (p *Pool) logger() Logger {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.Logger == nil {
+		p.Logger = NewDefaultLogger()
+	}
+	return p.Logger
+}
+
+// AddServer connects to the server described by cfg and adds it to the pool.
+// If a connection for this server already exists, it's returned as-is.
+func (p *Pool) AddServer(cfg ServerConfig) *Connection {
+	id := cfg.id()
+
+	p.mu.Lock()
+	if conn, ok := p.connections[id]; ok {
+		p.mu.Unlock()
+		return conn
+	}
+
+	// Connect only tags a Logger with "server" when it creates one itself
+	// (conn.Logger == nil); since we're handing it one already, tag it here
+	// instead so per-connection log lines still carry it.
+	logger := cfg.Logger
+	if logger == nil {
+		logger = p.logger()
+	}
+	logger = logger.With("server", id)
+
+	conn := &Connection{
+		Host:      cfg.Host,
+		Port:      cfg.Port,
+		Notifiers: cfg.Notifiers,
+		Logger:    logger,
+	}
+
+	// Without this, a connection that exhausts its reconnect attempts just
+	// stops silently and sits forgotten in p.connections forever. Removing
+	// it here lets a later AddServer for the same host start clean instead
+	// of finding a dead entry already occupying its id.
+	// OnGiveUp runs inside Reconnect's goroutine, which conn.wg counts, so
+	// RemoveServer (whose Disconnect waits on conn.wg) runs in a goroutine of
+	// its own that can finish once Reconnect has returned.
+	conn.OnGiveUp = func(err error) {
+		p.logger().Error("Giving up on server", "server", id, "error", err)
+		go p.RemoveServer(id)
+	}
+
+	p.connections[id] = conn
+	p.pending[id] = true
+	p.mu.Unlock()
+
+	// Connect (and the login/join calls below) dial and handshake over the
+	// network, so they must run without p.mu held: otherwise one slow or
+	// unreachable server serializes every other AddServer/RemoveServer/
+	// Broadcast call behind its connect timeout.
+	p.logger().Info("Adding server", "server", id)
+	conn.Connect()
+
+	p.mu.Lock()
+	_, stillWanted := p.connections[id]
+	delete(p.pending, id)
+	p.mu.Unlock()
+
+	if !stillWanted {
+		// RemoveServer ran while Connect was still in flight: it saw id in
+		// p.pending and left the Disconnect to us, since conn.socket/framer
+		// may not have existed yet for it to race against. Finish it now
+		// that Connect has returned.
+		conn.Disconnect()
+		return conn
+	}
+
+	if cfg.User != "" {
+		conn.safeGo(func() {
+			conn.SendLogin(cfg.User, cfg.Password)
+		})
+	}
+	for _, channel := range cfg.Channels {
+		channel := channel
+		conn.safeGo(func() {
+			conn.JoinChannel(channel)
+		})
+	}
+
+	return conn
+}
+
+// RemoveServer disconnects and forgets the connection for the given
+// "host:port" id. It's a no-op if the id isn't in the pool.
+func (p *Pool) RemoveServer(id string) {
+	p.mu.Lock()
+	conn, ok := p.connections[id]
+	if ok {
+		delete(p.connections, id)
+	}
+	pending := p.pending[id]
+	p.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	if pending {
+		// AddServer's Connect() call for this id hasn't returned yet, so
+		// conn.socket/conn.framer may still be nil. Deleting it from
+		// p.connections is enough: AddServer notices it's gone once Connect
+		// returns and disconnects it itself.
+		return
+	}
+
+	p.logger().Info("Removing server", "server", id)
+	conn.Disconnect()
+}
+
+// Broadcast calls fn once for every connection currently in the pool.
+func (p *Pool) Broadcast(fn func(*Connection)) {
+	p.mu.Lock()
+	conns := make([]*Connection, 0, len(p.connections))
+	for _, conn := range p.connections {
+		conns = append(conns, conn)
+	}
+	p.mu.Unlock()
+
+	for _, conn := range conns {
+		fn(conn)
+	}
+}
+
+// Shutdown disconnects every connection in the pool and empties it.
+func (p *Pool) Shutdown() {
+	p.mu.Lock()
+	conns := p.connections
+	p.connections = make(map[string]*Connection)
+	p.mu.Unlock()
+
+	for id, conn := range conns {
+		p.logger().Info("Shutting down server", "server", id)
+		conn.Disconnect()
+	}
+}
@@ -0,0 +1,56 @@
+package wired
+
+import "runtime"
+
+// safeGo runs fn in its own goroutine, joined by conn.wg, with a recover()
+// that keeps a panic in fn from ever reaching the process's default panic
+// handler. Every goroutine this package spawns (readData, processData,
+// sendPingReply, the ping-check loop, Reconnect, and each outbound send
+// kicked off from processData) should be started through safeGo instead of a
+// bare `go`, so a single misbehaving connection can't crash the whole server.
+//
+// A recovered panic is logged with its stack trace and treated the same way
+// as an unexpected socket error: the connection is torn down and a Reconnect
+// is attempted.
+func (conn *Connection) safeGo(fn func()) {
+	conn.wg.Add(1)
+
+	go func() {
+		defer conn.wg.Done()
+		defer func() {
+			if r := recover(); r != nil {
+				buf := make([]byte, 4096)
+				n := runtime.Stack(buf, false)
+
+				conn.Logger.Error("Recovered from panic", "panic", r, "stack", string(buf[:n]))
+				conn.fail()
+			}
+		}()
+
+		fn()
+	}()
+}
+
+// fail closes the connection's socket and lets the usual unexpected-
+// disconnection path in readData drive the reconnect, isolating the failure
+// to this one connection instead of the whole process.
+func (conn *Connection) fail() {
+	if conn.socket != nil {
+		conn.socket.Close()
+	}
+}
+
+// terminate closes the connection the same way fail does, but also cancels
+// conn.ctx so readData's error branch sees a cancelled context and returns
+// without reconnecting, exactly like a deliberate Disconnect. Use this
+// instead of fail for failures retrying can never fix — an incompatible
+// protocol version, a banned account, bad credentials — so they don't waste
+// up to the full backoff cap retrying something that can't resolve itself.
+func (conn *Connection) terminate() {
+	// Cancel before closing the socket, so the read error fail causes is
+	// never observed while conn.ctx is still live.
+	if conn.cancel != nil {
+		conn.cancel()
+	}
+	conn.fail()
+}
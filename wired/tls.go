@@ -0,0 +1,96 @@
+package wired
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"time"
+)
+
+// dial connects to address, over TLS if conn.TLSConfig is set and over
+// plain TCP otherwise.
+func (conn *Connection) dial(address string, timeout time.Duration) (net.Conn, error) {
+	if conn.TLSConfig == nil {
+		return net.DialTimeout("tcp", address, timeout)
+	}
+
+	tlsConfig := conn.TLSConfig.Clone()
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = conn.Host
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+
+	socket, err := tls.DialWithDialer(dialer, "tcp", address, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifyPinnedSPKI(socket.ConnectionState().PeerCertificates, conn.PinnedSPKISHA256); err != nil {
+		socket.Close()
+		return nil, err
+	}
+
+	conn.tlsConn = socket
+
+	return socket, nil
+}
+
+// verifyPinnedSPKI checks that at least one certificate in chain has a
+// SHA-256 hash of its subject public key info matching one of the
+// hex-encoded fingerprints in pins. It's a no-op if pins is empty, letting
+// the TLS handshake's own chain verification be the only check.
+func verifyPinnedSPKI(chain []*x509.Certificate, pins []string) error {
+	if len(pins) == 0 {
+		return nil
+	}
+
+	for _, cert := range chain {
+		hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		fingerprint := fmt.Sprintf("%x", hash)
+
+		for _, pin := range pins {
+			if fingerprint == pin {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("no certificate in the presented chain matched a pinned SPKI fingerprint")
+}
+
+// hasClientCertificate reports whether this connection is configured to
+// authenticate via a TLS client certificate, in which case the server logs
+// it in on its own instead of waiting for the usual guest SendLogin.
+func (conn *Connection) hasClientCertificate() bool {
+	return conn.TLSConfig != nil && len(conn.TLSConfig.Certificates) > 0
+}
+
+// PeerCertificates returns the certificate chain the server presented
+// during the TLS handshake, or nil if this connection isn't using TLS.
+func (conn *Connection) PeerCertificates() []*x509.Certificate {
+	if conn.tlsConn == nil {
+		return nil
+	}
+
+	return conn.tlsConn.ConnectionState().PeerCertificates
+}
+
+// tlsVersionName renders a tls.VersionTLS* constant as a human-readable
+// string for logging.
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	default:
+		return fmt.Sprintf("0x%04x", version)
+	}
+}
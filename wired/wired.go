@@ -11,14 +11,17 @@
 package wired
 
 import (
-	"bufio"
-	"encoding/xml"
+	"context"
+	"crypto/tls"
 	"fmt"
-	"github.com/mattprice/Go-APNs"
 	"io/ioutil"
 	"log"
+	"math"
+	"math/rand"
 	"net"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -33,6 +36,16 @@ const (
 	Connected
 )
 
+// Backoff parameters for Reconnect. Delays grow exponentially from
+// backoffBase, capped at backoffCap, and are randomized across the full
+// range on each attempt ("full jitter") so that many connections reconnecting
+// at once don't all hammer the server in lockstep.
+const (
+	backoffBase   = 1 * time.Second
+	backoffCap    = 5 * time.Minute
+	maxReconnects = 20
+)
+
 // There are a few I/O operations we should perform while the server is starting
 // so they aren't repeated for each connection we receive. For instance, reading
 // in the Wired specification files.
@@ -48,18 +61,20 @@ func init() {
 
 		file, err := ioutil.ReadFile(path)
 		if err != nil {
-			// We can't continue since Wired requires the specifications to connect.
-			log.Fatalf("Error loading Wired specifications: %v", err)
+			// A missing spec only affects connections that negotiate that
+			// one protocol version (sendCompatibilityCheck looks specs up by
+			// version and sends an empty specification for versions we don't
+			// have), so
+			// this is logged rather than fatal. A log.Fatalf here used to
+			// exit the process before main ever ran, which also meant `go
+			// test` couldn't load this package without every version's XML
+			// present on disk.
+			log.Printf("Error loading Wired specification for %s: %v", version, err)
+			continue
 		}
 
 		specs[version] = string(file)
 	}
-
-	// Connect to the push notification server.
-	err := apns.LoadCertificateFile(false, "certs/sandbox.pem")
-	if err != nil {
-		log.Fatalf("Error connecting to APNs: %v", err)
-	}
 }
 
 // Connection represents a connection to a Wired server.
@@ -75,33 +90,105 @@ type Connection struct {
 	Host string
 	Port int
 
+	// Notifiers are fanned out to whenever this connection observes a
+	// notable event (a user joining, a mention, a private message, ...).
+	// Configure one or more of APNSNotifier, FCMNotifier, WebPushNotifier, or
+	// WebhookNotifier, or anything else satisfying the Notifier interface.
+	Notifiers []Notifier
+
+	// OnGiveUp is called, if set, when Reconnect has exhausted maxReconnects
+	// attempts. It replaces the previous behavior of panicking the whole
+	// process, letting a Pool decide whether to remove the server, mark it
+	// dead, or keep retrying with a longer cap.
+	OnGiveUp func(err error)
+
+	// TLSConfig, if non-nil, makes Connect dial over TLS instead of plain
+	// TCP. ServerName defaults to Host when left empty. Set Certificates to
+	// authenticate with a client certificate, which supersedes the SHA1
+	// password login for servers that support it.
+	TLSConfig *tls.Config
+
+	// PinnedSPKISHA256 optionally restricts which server certificates are
+	// accepted, independent of TLSConfig's normal chain verification: if
+	// non-empty, at least one certificate in the presented chain must have a
+	// SHA-256 hash of its subject public key info matching one of these
+	// hex-encoded fingerprints.
+	PinnedSPKISHA256 []string
+
+	// Logger receives every log line this connection produces, tagged with
+	// contextual fields such as server=host:port, user_id=..., and
+	// transaction=.... If nil, Connect assigns a Logger built by
+	// NewDefaultLogger.
+	Logger Logger
+
 	version string
 	userID  string
+	nick    string
+
+	// ctx and cancel govern the lifecycle of every goroutine this connection
+	// spawns (readData, the ping-check loop, Reconnect's backoff sleep, ...).
+	// Disconnect cancels ctx so all of them exit instead of leaking across
+	// reconnects.
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	framer  *Framer
+	tlsConn *tls.Conn
 }
 
 // Connect connects to the server.
 func (conn *Connection) Connect() {
-	log.Println("Beginning socket connection...")
-
 	address := conn.Host + ":" + strconv.Itoa(conn.Port)
+
+	// The logger is only created (and tagged with "server") the first time we
+	// connect; Reconnect reuses it so the field isn't appended again on every
+	// retry.
+	if conn.Logger == nil {
+		conn.Logger = NewDefaultLogger()
+		conn.Logger = conn.Logger.With("server", address)
+	}
+
+	// Cancel the previous generation's context, if any, before replacing it.
+	// Without this, an unexpected disconnection (readData's error branch,
+	// which calls Reconnect without ever cancelling conn.ctx) leaves the
+	// current pingCheckLoop running forever on its old ctx.Done() select
+	// while Connect spawns a brand new one for the next generation. Cancelling
+	// here makes every Connect call, not just Disconnect, retire the previous
+	// generation's goroutines.
+	if conn.cancel != nil {
+		conn.cancel()
+	}
+	conn.ctx, conn.cancel = context.WithCancel(context.Background())
+
+	conn.Logger.Info("Beginning socket connection...")
+
 	timeout := 15 * time.Second
 
-	// Attempt a socket connection to the server.
-	socket, err := net.DialTimeout("tcp", address, timeout)
+	// Attempt a socket connection to the server, over TLS if configured.
+	socket, err := conn.dial(address, timeout)
 	conn.socket = socket
 
 	// If the connection failed, attempt to reconnect.
 	if err != nil {
-		log.Printf("Connection failed: %v\n", err)
-		go conn.Reconnect()
+		conn.Logger.Error("Connection failed", "error", err)
+		conn.safeGo(conn.Reconnect)
 		return
 	}
 
+	if conn.tlsConn != nil {
+		state := conn.tlsConn.ConnectionState()
+		conn.Logger.Info("TLS handshake complete",
+			"version", tlsVersionName(state.Version),
+			"cipher_suite", tls.CipherSuiteName(state.CipherSuite))
+	}
+
 	// If the connection was successful, reset the retryCount.
 	conn.retryCount = 0
+	conn.framer = NewFramer(conn.socket)
 
 	// Start sending Wired connection info.
-	log.Println("Sending Wired handshake...")
+	conn.Logger.Info("Sending Wired handshake...")
 	parameters := map[string]string{
 		"p7.handshake.version":          "1.0",
 		"p7.handshake.protocol.name":    "Wired",
@@ -110,26 +197,34 @@ func (conn *Connection) Connect() {
 	conn.sendTransaction("p7.handshake.client_handshake", parameters)
 
 	// Start listening for server responses.
-	go conn.readData()
+	conn.safeGo(conn.readData)
+	conn.safeGo(conn.pingCheckLoop)
+}
 
-	// BUG(mattprice): This goroutine is never closed when the server disconnects.
-	// On reconnection, another goroutine is spawned.
-	go func() {
-		// Check on the connection every 90 seconds.
-		timer := time.Tick(90 * time.Second)
-		for _ = range timer {
+// pingCheckLoop periodically makes sure we're still sending ping replies even
+// if the server hasn't asked for one in a while. It exits as soon as conn.ctx
+// is cancelled, so Disconnect (or a Reconnect that replaces the socket) never
+// leaves a stray copy of this loop running in the background.
+func (conn *Connection) pingCheckLoop() {
+	// Check on the connection every 90 seconds.
+	ticker := time.NewTicker(90 * time.Second)
+	defer ticker.Stop()
 
+	for {
+		select {
+		case <-conn.ctx.Done():
+			return
+		case <-ticker.C:
 			if conn.status == Connected {
 				// If we haven't received a ping request in 60 seconds, send a reply anyway.
 				duration := time.Since(conn.lastPing)
 				if duration.Seconds() >= 60 {
-					log.Println("Sending proactive ping reply...")
-					go conn.sendPingReply()
+					conn.Logger.Debug("Sending proactive ping reply...")
+					conn.safeGo(conn.sendPingReply)
 				}
 			}
-
 		}
-	}()
+	}
 }
 
 // Reconnect reconnects to the server.
@@ -137,44 +232,81 @@ func (conn *Connection) Reconnect() {
 	conn.status = Reconnecting
 	conn.retryCount++
 
-	// Stop trying to reconnect after 20 failed attempts.
-	// With a 15 second delay, and a 15 second connection timeout, that ends up
-	// being about 10 minutes of limbo before we give up.
-	if conn.retryCount > 20 {
+	// Stop trying to reconnect after maxReconnects failed attempts and let
+	// OnGiveUp decide what happens to this connection instead of panicking
+	// the whole process.
+	if conn.retryCount > maxReconnects {
 		conn.status = Disconnected
-		log.Panicln("*** Unable to reconnect after 20 tries. ***")
+
+		err := fmt.Errorf("unable to reconnect to %s:%d after %d attempts", conn.Host, conn.Port, maxReconnects)
+		conn.Logger.Error("Giving up on reconnecting.", "retry", conn.retryCount)
+
+		if conn.OnGiveUp != nil {
+			conn.OnGiveUp(err)
+		}
+		return
 	}
 
-	// Wait 15 seconds between reconnections.
-	// TODO: Start with a smaller delay and then increase it with each retry.
-	delay := 15 * time.Second
-	log.Printf("Reconnecting in %v. Attempt %v.", delay, conn.retryCount)
-	time.Sleep(delay)
+	delay := backoffDelay(conn.retryCount)
+	conn.Logger.Info("Reconnecting...", "delay", delay, "retry", conn.retryCount)
+
+	// Wait for the backoff delay to elapse, but give up early if Disconnect
+	// cancels the connection's context while we're waiting.
+	select {
+	case <-conn.ctx.Done():
+		return
+	case <-time.After(delay):
+	}
 
 	conn.Connect()
 }
 
+// backoffDelay returns an exponential backoff delay for the given attempt
+// number, randomized across the full range ("full jitter") and capped at
+// backoffCap.
+func backoffDelay(attempt int) time.Duration {
+	capped := float64(backoffBase) * math.Pow(2, float64(attempt))
+	if capped > float64(backoffCap) {
+		capped = float64(backoffCap)
+	}
+
+	return time.Duration(rand.Int63n(int64(capped)))
+}
+
 // Disconnect disconnects from the server.
 func (conn *Connection) Disconnect() {
-	log.Println("Disconnecting from server...")
-
-	// Alert the Wired server that we're disconnecting.
-	parameters := map[string]string{
-		"wired.user.id":                 conn.userID,
-		"wired.user.disconnect_message": "",
+	conn.Logger.Info("Disconnecting from server...")
+
+	// If we never reached a working connection (every dial attempt failed,
+	// e.g. right before OnGiveUp gives up on an unreachable server), there's
+	// no handshake to say goodbye over and no socket to close.
+	if conn.framer != nil {
+		parameters := map[string]string{
+			"wired.user.id":                 conn.userID,
+			"wired.user.disconnect_message": "",
+		}
+		conn.sendTransaction("wired.user.disconnect_user", parameters)
 	}
-	conn.sendTransaction("wired.user.disconnect_user", parameters)
 
-	// Close the socket connection.
 	conn.status = Disconnected
-	conn.socket.Close()
+	if conn.socket != nil {
+		conn.socket.Close()
+	}
+
+	// Cancel the connection's context so readData, the ping-check loop, and
+	// any pending Reconnect backoff sleep all terminate, then wait for them
+	// to finish before returning.
+	if conn.cancel != nil {
+		conn.cancel()
+	}
+	conn.wg.Wait()
 }
 
 // SendLogin sends the user's login information to the Wired server.
 //
 // The password must be converted to a SHA1 digest before sending it to this function.
 func (conn *Connection) SendLogin(user, password string) {
-	log.Println("Sending login information...")
+	conn.Logger.Info("Sending login information...", "user_id", user)
 
 	// Send the user login information to the Wired server.
 	parameters := map[string]string{
@@ -186,7 +318,7 @@ func (conn *Connection) SendLogin(user, password string) {
 
 // SetNick sets the user's nickname.
 func (conn *Connection) SetNick(nick string) {
-	log.Println("Attempting to change nick...")
+	conn.Logger.Debug("Attempting to change nick...")
 
 	parameters := map[string]string{
 		"wired.user.nick": nick,
@@ -196,7 +328,7 @@ func (conn *Connection) SetNick(nick string) {
 
 // SetStatus sets the user's status.
 func (conn *Connection) SetStatus(status string) {
-	log.Println("Attempting to change status...")
+	conn.Logger.Debug("Attempting to change status...")
 
 	parameters := map[string]string{
 		"wired.user.status": status,
@@ -206,7 +338,7 @@ func (conn *Connection) SetStatus(status string) {
 
 // SetIcon sets the user's avatar.
 func (conn *Connection) SetIcon(icon string) {
-	log.Println("Attempting to change icon...")
+	conn.Logger.Debug("Attempting to change icon...")
 
 	parameters := map[string]string{
 		"wired.user.icon": icon,
@@ -216,7 +348,7 @@ func (conn *Connection) SetIcon(icon string) {
 
 // SetIdle sets the user as idle.
 func (conn *Connection) SetIdle() {
-	log.Println("Attempting to set user as idle...")
+	conn.Logger.Debug("Attempting to set user as idle...")
 
 	parameters := map[string]string{
 		"wired.user.idle": "YES",
@@ -228,7 +360,7 @@ func (conn *Connection) SetIdle() {
 //
 // Under most circumstances users will only ever join channel 1, the public channel.
 func (conn *Connection) JoinChannel(channel string) {
-	log.Printf("Attempting to join channel %s...\n", channel)
+	conn.Logger.Debug("Attempting to join channel...", "channel", channel)
 
 	// Attempt to join the channel.
 	parameters := map[string]string{
@@ -239,14 +371,14 @@ func (conn *Connection) JoinChannel(channel string) {
 
 // sendAcknowledgement sends an acknowledgement to the Wired server.
 func (conn *Connection) sendAcknowledgement() {
-	log.Println("Sending acknowledgement...")
+	conn.Logger.Debug("Sending acknowledgement...")
 
 	conn.sendTransaction("p7.handshake.acknowledge")
 }
 
 // sendPingReply replies to a ping request from the Wired server.
 func (conn *Connection) sendPingReply() {
-	// log.Println("Attempting to send ping reply...")
+	conn.Logger.Trace("Attempting to send ping reply...")
 
 	conn.sendTransaction("wired.ping")
 }
@@ -257,7 +389,7 @@ func (conn *Connection) sendPingReply() {
 // certain characters be encoded before sending. To save processing time the XML
 // should be pre-encoded. To save bandwidth the documentation lines should be removed.
 func (conn *Connection) sendCompatibilityCheck() {
-	log.Println("Sending compatibility check...")
+	conn.Logger.Info("Sending compatibility check...")
 
 	parameters := map[string]string{
 		"p7.compatibility_check.specification": specs[conn.version],
@@ -271,7 +403,7 @@ func (conn *Connection) sendCompatibilityCheck() {
 // In the future, this should report the same information as the Wired version
 // that's connecting to the Push server.
 func (conn *Connection) sendClientInformation() {
-	log.Println("Sending client information...")
+	conn.Logger.Info("Sending client information...")
 
 	parameters := map[string]string{
 		"wired.info.application.name":    "Wired Client",
@@ -290,20 +422,15 @@ func (conn *Connection) sendClientInformation() {
 // All transactions require a transaction name, but the parameters map is optional.
 // Only the first parameters map is read. Multiple parameter maps will be ignored.
 func (conn *Connection) sendTransaction(transaction string, parameters ...map[string]string) {
-	generatedXML := `<?xml version="1.0" encoding="UTF-8"?>`
-	generatedXML += `<p7:message name="` + transaction + `" xmlns:p7="http://www.zankasoftware.com/P7/Message">`
-
-	if parameters != nil {
-		for key, value := range parameters[0] {
-			generatedXML += `<p7:field name="` + key + `">` + value + `</p7:field>`
-		}
+	var fields map[string]string
+	if len(parameters) > 0 {
+		fields = parameters[0]
 	}
 
-	generatedXML += "</p7:message>\r\n"
+	conn.Logger.Trace("Sending transaction", "transaction", transaction)
 
-	_, err := conn.socket.Write([]byte(generatedXML))
-	if err != nil {
-		log.Printf("Error writing data to socket: %v", err)
+	if err := conn.framer.WriteMessage(transaction, fields); err != nil {
+		conn.Logger.Error("Error writing data to socket", "transaction", transaction, "error", err)
 	}
 }
 
@@ -314,56 +441,37 @@ func (conn *Connection) sendTransaction(transaction string, parameters ...map[st
 // in its own goroutine until it recieves data and then immediately pass it off
 // to another goroutine for processing.
 func (conn *Connection) readData() {
-	reader := bufio.NewReader(conn.socket)
-
 	for {
-		// log.Println("Attempting to read data from the socket.")
+		conn.Logger.Trace("Attempting to read data from the socket.")
 
-		data, err := reader.ReadBytes('\r')
+		message, err := conn.framer.ReadMessage()
 		if err != nil {
-			log.Printf("Error reading data from socket: %v", err)
-			log.Println("*** Server disconnected unexpectedly. ***")
-
-			go conn.Reconnect()
-			break
-		}
+			// If our own context was cancelled, the socket was closed by
+			// Disconnect and this isn't an unexpected disconnection.
+			if conn.ctx.Err() != nil {
+				return
+			}
 
-		go conn.processData(&data)
-	}
-}
+			conn.Logger.Error("Error reading data from socket", "error", err)
+			conn.Logger.Info("Server disconnected unexpectedly.")
 
-// processData parses and acts on messages sent by the Wired server.
-func (conn *Connection) processData(data *[]byte) {
-	defer func() {
-		if r := recover(); r != nil {
-			// Recovered from panic! But I haven't decided what to do yet...
-			panic(r)
+			conn.safeGo(conn.Reconnect)
+			return
 		}
-	}()
 
-	type p7Field struct {
-		Name  string `xml:"name,attr"`
-		Value string `xml:",innerxml"`
-	}
-
-	type p7Message struct {
-		Name   string    `xml:"name,attr"`
-		Fields []p7Field `xml:"field"`
-	}
-
-	// Decode the XML document.
-	message := new(p7Message)
-	err := xml.Unmarshal(*data, &message)
-	if err != nil {
-		log.Printf("Error decoding XML document: %v\n%v", err, string(*data))
-		return
+		conn.safeGo(func() {
+			conn.processData(message)
+		})
 	}
+}
 
+// processData acts on messages sent by the Wired server.
+func (conn *Connection) processData(message *p7Message) {
 	if message.Name == "p7.handshake.server_handshake" {
 		// Server Handshake
-		log.Println("Received handshake.")
+		conn.Logger.Info("Received handshake.")
 
-		go conn.sendAcknowledgement()
+		conn.safeGo(conn.sendAcknowledgement)
 
 		// Just incase the server sends fields out of order, we don't send the
 		// compatibility check until after processing everything, when we're certain
@@ -380,47 +488,61 @@ func (conn *Connection) processData(data *[]byte) {
 		}
 
 		if sendCheck {
-			go conn.sendCompatibilityCheck()
+			conn.safeGo(conn.sendCompatibilityCheck)
 		} else {
-			go conn.sendClientInformation()
+			conn.safeGo(conn.sendClientInformation)
 		}
 	} else if message.Name == "p7.compatibility_check.status" {
 		// Compatibility Check
-		log.Println("Received compatibility status.")
+		conn.Logger.Info("Received compatibility status.")
 
 		for _, field := range message.Fields {
 			if field.Name == "p7.compatibility_check.status" {
 				if field.Value == "1" {
-					go conn.sendClientInformation()
+					conn.safeGo(conn.sendClientInformation)
 				} else {
-					// BUG(mattprice): Panic will crash the entire server right now.
-					// We need to do some defer()'s and recover()'s in the main goroutine
-					// so that only this Connection closes.
-					log.Panic("Compatibility mismatch.")
+					// Only this connection is torn down; previously this was a
+					// log.Panic that crashed the entire server. The version
+					// mismatch won't fix itself on retry, so terminate instead
+					// of letting readData hand this off to Reconnect.
+					conn.Logger.Error("Compatibility mismatch.")
+					conn.terminate()
 				}
 			}
 		}
 	} else if message.Name == "wired.server_info" {
 		// Server Info
-		log.Println("Received server info.")
+		conn.Logger.Debug("Received server info.")
 
 		// Server info is periodcially sent out while connected, so we need to
-		// check the connection status before logging in.
-		if conn.status != Connected {
-			go conn.SendLogin("guest", "da39a3ee5e6b4b0d3255bfef95601890afd80709")
+		// check the connection status before logging in. A server that
+		// authenticated us via a TLS client certificate logs us in on its
+		// own; sending the guest login on top of that would just bounce
+		// off of an already-logged-in connection.
+		if conn.status != Connected && !conn.hasClientCertificate() {
+			conn.safeGo(func() {
+				conn.SendLogin("guest", "da39a3ee5e6b4b0d3255bfef95601890afd80709")
+			})
 		}
 	} else if message.Name == "wired.login" {
 		// Login Successful
-		log.Println("Login was successful.")
-
 		for _, field := range message.Fields {
 			if field.Name == "wired.user.id" {
 				conn.userID = field.Value
 			}
 		}
 
-		go func() {
-			conn.SetNick("Triforce")
+		// conn.Logger is read concurrently by readData, pingCheckLoop, and every
+		// in-flight sendTransaction/notify goroutine, so it can't be mutated
+		// here without a race; passing user_id as a keyval on this one line
+		// also avoids growing the logger's field slice by one more duplicate
+		// "user_id" pair on every guest-login reconnect.
+		conn.Logger.Info("Login was successful.", "user_id", conn.userID)
+
+		conn.nick = "Triforce"
+
+		conn.safeGo(func() {
+			conn.SetNick(conn.nick)
 			conn.SetStatus("The APNs of Wired")
 			conn.SetIcon(`iVBORw0KGgoAAAANSUhEUgAAAEAAAABACAQAAAAAYLlVAAABHElEQVR4A
 				e3XsY1EIRCD4WmCUiiElqYgeqISjmCDFdnbT4Lgnh3/AciGmXj1ClWWr2osX1SNuVxvn
@@ -435,36 +557,89 @@ func (conn *Connection) processData(data *[]byte) {
 
 			// TODO: Check to see if the user should actually be considered idle.
 			conn.SetIdle()
-		}()
+		})
 	} else if message.Name == "wired.send_ping" {
 		conn.lastPing = time.Now()
 
 		// Ping Request
-		go conn.sendPingReply()
+		conn.safeGo(conn.sendPingReply)
 	} else if message.Name == "wired.error" {
 		// Wired Errors
 		for _, field := range message.Fields {
 			if field.Value == "wired.error.login_failed" {
-				log.Panicln("Login failed:", "Username or password is incorrect.")
+				// Only this connection is torn down; previously this was a
+				// log.Panicln that crashed the entire server. Bad credentials
+				// won't succeed on retry, so terminate instead of letting
+				// readData hand this off to Reconnect.
+				conn.Logger.Error("Login failed.", "reason", "username or password is incorrect")
+				conn.terminate()
 			} else if field.Value == "wired.banned" {
-				log.Panicln("Login failed:", "User is banned from this server.")
+				conn.Logger.Error("Login failed.", "reason", "user is banned from this server")
+				conn.terminate()
 			} else {
-				log.Println("*** ERROR:", field.Value, "***")
+				conn.Logger.Error("Received error from server", "error", field.Value)
 			}
 		}
 	} else if message.Name == "wired.chat.user_join" {
 		// User Joined the Channel
 		for _, field := range message.Fields {
 			if field.Name == "wired.user.nick" {
-				// Send a push notification to my iPhone.
-				payload := &apns.Notification{
-					Alert:   fmt.Sprintf("%s has logged into Cunning Giraffe.", field.Value),
-					Sandbox: true,
-				}
-				payload.SetExpiryDuration(24 * time.Hour)
-				payload.SendTo("01b67b3ffc8405c1d9ece77b6e4747b97ecdacb4ce940af1fca260b9a0311d80")
+				conn.notify(Event{
+					Type: EventUserJoin,
+					Nick: field.Value,
+					Time: time.Now(),
+				})
 			}
 		}
+	} else if message.Name == "wired.chat.user_leave" {
+		// User Left the Channel
+		for _, field := range message.Fields {
+			if field.Name == "wired.user.nick" {
+				conn.notify(Event{
+					Type: EventUserPart,
+					Nick: field.Value,
+					Time: time.Now(),
+				})
+			}
+		}
+	} else if message.Name == "wired.chat.say" {
+		// Chat Message
+		var nick, text string
+		for _, field := range message.Fields {
+			switch field.Name {
+			case "wired.user.nick":
+				nick = field.Value
+			case "wired.chat.say":
+				text = field.Value
+			}
+		}
+
+		if conn.nick != "" && strings.Contains(strings.ToLower(text), strings.ToLower(conn.nick)) {
+			conn.notify(Event{
+				Type:    EventMention,
+				Nick:    nick,
+				Message: text,
+				Time:    time.Now(),
+			})
+		}
+	} else if message.Name == "wired.message.message" {
+		// Private Message
+		var nick, text string
+		for _, field := range message.Fields {
+			switch field.Name {
+			case "wired.user.nick":
+				nick = field.Value
+			case "wired.message.message":
+				text = field.Value
+			}
+		}
+
+		conn.notify(Event{
+			Type:    EventPrivateMessage,
+			Nick:    nick,
+			Message: text,
+			Time:    time.Now(),
+		})
 	} else {
 		// log.Printf("%q\n", message.Name)
 		// for _, field := range message.Fields {